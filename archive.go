@@ -0,0 +1,271 @@
+package urldownloader
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var ErrUnsupportedArchive error = errors.New("Unsupported archive format")
+var ErrArchivePathTraversal error = errors.New("Archive entry attempts path traversal")
+
+func (o *Options) SetUnarchive(unarchive bool) {
+	o.unarchive = unarchive
+}
+
+func (o *Options) SetUnarchiveStripComponents(strip int) {
+	o.unarchiveStrip = strip
+}
+
+// sizeGuard enforces a cumulative byte limit across every entry of an
+// archive, so a small compressed payload cannot expand into an unbounded
+// number of bytes on disk (a zip bomb).
+type sizeGuard struct {
+	limit   int64
+	written int64
+}
+
+func (g *sizeGuard) copy(dst io.Writer, src io.Reader) error {
+
+	var remaining int64
+	var n int64
+	var err error
+
+	if g.limit == 0 {
+		_, err = io.Copy(dst, src)
+		return err
+	}
+
+	remaining = g.limit - g.written
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	if n, err = io.Copy(dst, io.LimitReader(src, remaining+1)); err != nil {
+		return err
+	}
+
+	g.written += n
+
+	if n > remaining {
+		return ErrMaxSizeExceeded
+	}
+
+	return nil
+
+}
+
+// unarchiveFile extracts the archive at archivePath (already detected as
+// mimeType) into destDir, enforcing maxSize against the uncompressed
+// total. Only the formats covered by the stdlib (zip, tar, tar.gz) are
+// supported, keeping the core package free of extra decompressor deps.
+func unarchiveFile(archivePath string, mimeType string, destDir string, stripComponents int, maxSize int64) error {
+
+	switch mimeType {
+	case "application/zip":
+		return unarchiveZip(archivePath, destDir, stripComponents, maxSize)
+	case "application/x-tar":
+		return unarchiveTarFile(archivePath, destDir, stripComponents, maxSize)
+	case "application/gzip", "application/x-gzip":
+		return unarchiveGzip(archivePath, destDir, stripComponents, maxSize)
+	default:
+		return ErrUnsupportedArchive
+	}
+
+}
+
+func unarchiveTarFile(archivePath string, destDir string, stripComponents int, maxSize int64) error {
+
+	var file *os.File
+	var err error
+
+	if file, err = os.Open(archivePath); err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return extractTar(file, destDir, stripComponents, maxSize)
+
+}
+
+func unarchiveGzip(archivePath string, destDir string, stripComponents int, maxSize int64) error {
+
+	var file *os.File
+	var gzReader *gzip.Reader
+	var err error
+
+	if file, err = os.Open(archivePath); err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	if gzReader, err = gzip.NewReader(file); err != nil {
+		return err
+	}
+
+	defer gzReader.Close()
+
+	return extractTar(gzReader, destDir, stripComponents, maxSize)
+
+}
+
+func extractTar(reader io.Reader, destDir string, stripComponents int, maxSize int64) error {
+
+	var tr *tar.Reader
+	var header *tar.Header
+	var name string
+	var destPath string
+	var outFile *os.File
+	var err error
+	var guard sizeGuard
+
+	tr = tar.NewReader(reader)
+	guard = sizeGuard{limit: maxSize}
+
+	for {
+
+		header, err = tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if name, err = sanitizeEntryName(header.Name, stripComponents); err != nil {
+			return err
+		}
+
+		if name == "" {
+			continue
+		}
+
+		destPath = filepath.Join(destDir, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(destPath, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+				return err
+			}
+			if outFile, err = os.Create(destPath); err != nil {
+				return err
+			}
+			err = guard.copy(outFile, tr)
+			outFile.Close()
+			if err != nil {
+				return err
+			}
+		}
+
+	}
+
+	return nil
+
+}
+
+func unarchiveZip(archivePath string, destDir string, stripComponents int, maxSize int64) error {
+
+	var reader *zip.ReadCloser
+	var entry *zip.File
+	var name string
+	var destPath string
+	var src io.ReadCloser
+	var outFile *os.File
+	var err error
+	var guard sizeGuard
+
+	if reader, err = zip.OpenReader(archivePath); err != nil {
+		return err
+	}
+
+	defer reader.Close()
+
+	guard = sizeGuard{limit: maxSize}
+
+	for _, entry = range reader.File {
+
+		if name, err = sanitizeEntryName(entry.Name, stripComponents); err != nil {
+			return err
+		}
+
+		if name == "" {
+			continue
+		}
+
+		destPath = filepath.Join(destDir, name)
+
+		if entry.FileInfo().IsDir() {
+			if err = os.MkdirAll(destPath, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err = os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return err
+		}
+
+		if src, err = entry.Open(); err != nil {
+			return err
+		}
+
+		if outFile, err = os.Create(destPath); err != nil {
+			src.Close()
+			return err
+		}
+
+		err = guard.copy(outFile, src)
+		outFile.Close()
+		src.Close()
+
+		if err != nil {
+			return err
+		}
+
+	}
+
+	return nil
+
+}
+
+// sanitizeEntryName cleans an archive entry's name, applies
+// stripComponents leading path segments, and rejects any entry that would
+// escape destDir (absolute paths or ".." segments). An empty result with
+// a nil error means the entry was fully stripped and should be skipped.
+func sanitizeEntryName(name string, stripComponents int) (string, error) {
+
+	var cleaned string
+	var parts []string
+
+	cleaned = path.Clean(strings.ReplaceAll(name, "\\", "/"))
+
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrArchivePathTraversal
+	}
+
+	if cleaned == "." {
+		return "", nil
+	}
+
+	parts = strings.Split(cleaned, "/")
+	if stripComponents > 0 {
+		if stripComponents >= len(parts) {
+			return "", nil
+		}
+		parts = parts[stripComponents:]
+	}
+
+	return strings.Join(parts, "/"), nil
+
+}
@@ -0,0 +1,42 @@
+package urldownloader
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSanitizeEntryNameRejectsTraversal(t *testing.T) {
+
+	var names []string
+	var name string
+	var err error
+
+	names = []string{
+		"../etc/passwd",
+		"..",
+		"a/../../b",
+		"/etc/passwd",
+	}
+
+	for _, name = range names {
+		if _, err = sanitizeEntryName(name, 0); !errors.Is(err, ErrArchivePathTraversal) {
+			t.Errorf("sanitizeEntryName(%q, 0) = %v, want ErrArchivePathTraversal", name, err)
+		}
+	}
+
+}
+
+func TestSanitizeEntryNameStripsComponents(t *testing.T) {
+
+	var result string
+	var err error
+
+	if result, err = sanitizeEntryName("archive/inner/file.txt", 1); err != nil {
+		t.Fatalf("sanitizeEntryName() returned error: %v", err)
+	}
+
+	if result != "inner/file.txt" {
+		t.Errorf("sanitizeEntryName() = %q, want %q", result, "inner/file.txt")
+	}
+
+}
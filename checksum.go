@@ -0,0 +1,129 @@
+package urldownloader
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+var ErrChecksumMismatch error = errors.New("Checksum mismatch")
+var ErrUnsupportedChecksumAlgorithm error = errors.New("Unsupported checksum algorithm")
+var ErrEmptyChecksumFile error = errors.New("Checksum file is empty")
+
+func (o *Options) SetExpectedChecksum(algo string, hex string) {
+	o.checksumAlgo = strings.ToLower(algo)
+	o.checksumHex = strings.ToLower(hex)
+}
+
+func (o *Options) SetChecksumURL(checksumURL *url.URL) {
+	o.checksumURL = checksumURL
+}
+
+func newChecksumHash(algo string) (hash.Hash, error) {
+
+	switch strings.ToLower(algo) {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "md5":
+		return md5.New(), nil
+	default:
+		return nil, ErrUnsupportedChecksumAlgorithm
+	}
+
+}
+
+// hashFile computes the hex-encoded algo digest of the file at path.
+func hashFile(path string, algo string) (string, error) {
+
+	var file *os.File
+	var hasher hash.Hash
+	var err error
+
+	if file, err = os.Open(path); err != nil {
+		return "", err
+	}
+
+	defer file.Close()
+
+	if hasher, err = newChecksumHash(algo); err != nil {
+		return "", err
+	}
+
+	if _, err = io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+
+}
+
+// verifyChecksumFile hashes the file at path with algo and compares it
+// against expectedHex, which must already be lowercase hex.
+func verifyChecksumFile(path string, algo string, expectedHex string) error {
+
+	var actual string
+	var err error
+
+	if actual, err = hashFile(path, algo); err != nil {
+		return err
+	}
+
+	if actual != expectedHex {
+		return ErrChecksumMismatch
+	}
+
+	return nil
+
+}
+
+// fetchChecksum retrieves a checksum sidecar file (as referenced by
+// SetChecksumURL, e.g. "<url>.sha256") and extracts the hex digest,
+// following the convention of tools like sha256sum where the file
+// contains "<hex>  <filename>".
+func fetchChecksum(ctx context.Context, checksumURL *url.URL) (string, error) {
+
+	var request *http.Request
+	var response *http.Response
+	var err error
+	var scanner *bufio.Scanner
+	var fields []string
+
+	if request, err = http.NewRequestWithContext(ctx, http.MethodGet, checksumURL.String(), nil); err != nil {
+		return "", err
+	}
+
+	if response, err = http.DefaultClient.Do(request); err != nil {
+		return "", err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", ErrHTTPStatus{StatusCode: response.StatusCode}
+	}
+
+	scanner = bufio.NewScanner(response.Body)
+	if !scanner.Scan() {
+		return "", ErrEmptyChecksumFile
+	}
+
+	fields = strings.Fields(scanner.Text())
+	if len(fields) == 0 {
+		return "", ErrEmptyChecksumFile
+	}
+
+	return strings.ToLower(fields[0]), nil
+
+}
@@ -0,0 +1,25 @@
+package urldownloader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyChecksumFileMismatch(t *testing.T) {
+
+	var path string
+	var err error
+
+	path = filepath.Join(t.TempDir(), "payload")
+
+	if err = os.WriteFile(path, []byte("hello world"), 0644); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+
+	if err = verifyChecksumFile(path, "sha256", "0000000000000000000000000000000000000000000000000000000000000000"); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("verifyChecksumFile() = %v, want ErrChecksumMismatch", err)
+	}
+
+}
@@ -0,0 +1,51 @@
+package urldownloader
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type stubFetcher struct {
+	body string
+}
+
+func (f *stubFetcher) Fetch(ctx context.Context, url *url.URL, dst io.Writer) (http.Header, error) {
+	var err error
+	_, err = io.WriteString(dst, f.body)
+	return nil, err
+}
+
+func TestDownloadFileHonorsRegisteredHTTPFetcher(t *testing.T) {
+
+	var target *url.URL
+	var err error
+	var data []byte
+	var filename string
+
+	RegisterFetcher("http", &stubFetcher{body: "stub content"})
+	defer RegisterFetcher("http", &httpFetcher{})
+
+	if target, err = url.Parse("http://example.invalid/file.txt"); err != nil {
+		t.Fatalf("url.Parse() returned error: %v", err)
+	}
+
+	filename = filepath.Join(t.TempDir(), "file.txt")
+
+	if err = downloadFile(context.Background(), filename, target, 0, nil, nil, "", "", "", false, 1, 0); err != nil {
+		t.Fatalf("downloadFile() returned error: %v", err)
+	}
+
+	if data, err = os.ReadFile(filename); err != nil {
+		t.Fatalf("ReadFile() returned error: %v", err)
+	}
+
+	if string(data) != "stub content" {
+		t.Errorf("downloaded content = %q, want %q", string(data), "stub content")
+	}
+
+}
@@ -1,6 +1,7 @@
 package urldownloader
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -17,10 +19,24 @@ import (
 // Options
 
 type Options struct {
-	maxSize    int64
-	baseFolder string
-	mimeType   string
-	mimeGroups []string
+	maxSize              int64
+	baseFolder           string
+	mimeType             string
+	mimeGroups           []string
+	httpClient           *http.Client
+	requestHeaders       http.Header
+	userAgent            string
+	checksumAlgo         string
+	checksumHex          string
+	checksumURL          *url.URL
+	resumable            bool
+	retryAttempts        int
+	retryBackoff         time.Duration
+	mimeDetector         func(io.Reader) (string, string, error)
+	renameByDetectedMime bool
+	unarchive            bool
+	unarchiveStrip       int
+	contentAddressed     bool
 }
 
 func (o *Options) SetMaxSize(size int64) {
@@ -39,12 +55,56 @@ func (o *Options) SetMimeGroups(groups ...string) {
 	o.mimeGroups = groups
 }
 
+func (o *Options) SetHTTPClient(client *http.Client) {
+	o.httpClient = client
+}
+
+func (o *Options) SetRequestHeaders(headers http.Header) {
+	o.requestHeaders = headers
+}
+
+func (o *Options) SetUserAgent(agent string) {
+	o.userAgent = agent
+}
+
 var ErrMaxSizeExceeded error = errors.New("Max byte size exceeded")
 var ErrUnknownFilename error = errors.New("Unknown filename")
 var ErrWrongMimeType error = errors.New("Wrong mime type")
 var ErrWrongMimeGroup error = errors.New("Wrong mime group")
 
+// ErrHTTPStatus is returned when the remote server answers a plain
+// download with anything other than 200 OK, or a resumed (ranged) download
+// with a 5xx status, instead of letting an error page be written to disk
+// as if it were the downloaded file.
+type ErrHTTPStatus struct {
+	StatusCode int
+}
+
+func (e ErrHTTPStatus) Error() string {
+	return fmt.Sprintf("Unexpected HTTP status: %v", e.StatusCode)
+}
+
 func DownloadFileFromUrl(url *url.URL, options ...func(*Options) error) (string, error) {
+	return DownloadFileFromUrlContext(context.Background(), url, options...)
+}
+
+func DownloadFileFromUrlContext(ctx context.Context, url *url.URL, options ...func(*Options) error) (string, error) {
+
+	var result *DownloadFileFromUrlResult
+	var err error
+
+	if result, err = DownloadFileFromUrlResultContext(ctx, url, options...); err != nil {
+		return "", err
+	}
+
+	return result.Path, nil
+
+}
+
+// DownloadFileFromUrlResultContext behaves like DownloadFileFromUrlContext
+// but also reports the SHA-256 hash of the stored content when
+// SetContentAddressed is enabled.
+func DownloadFileFromUrlResultContext(ctx context.Context, url *url.URL, options ...func(*Options) error) (*DownloadFileFromUrlResult, error) {
 
 	var id string
 	var path string
@@ -55,6 +115,13 @@ func DownloadFileFromUrl(url *url.URL, options ...func(*Options) error) (string,
 	var defaultOptions *Options
 	var op func(*Options) error
 	var mimeType string
+	var extension string
+	var extractDir string
+	var checksumAlgo string
+	var checksumHex string
+	var cachedDigest string
+	var cachedPath string
+	var cached bool
 
 	// options
 
@@ -63,11 +130,39 @@ func DownloadFileFromUrl(url *url.URL, options ...func(*Options) error) (string,
 		"/tmp",
 		"",
 		nil,
+		nil,
+		nil,
+		"",
+		"",
+		"",
+		nil,
+		false,
+		0,
+		0,
+		nil,
+		false,
+		false,
+		0,
+		false,
 	}
 
 	for _, op = range options {
 		if err = op(defaultOptions); err != nil {
-			return "", err
+			return nil, err
+		}
+	}
+
+	// checksum
+
+	checksumAlgo = defaultOptions.checksumAlgo
+	checksumHex = defaultOptions.checksumHex
+
+	if defaultOptions.checksumURL != nil {
+		if checksumHex, err = fetchChecksum(ctx, defaultOptions.checksumURL); err != nil {
+			return nil, err
+		}
+		if checksumAlgo == "" {
+			checksumAlgo = "sha256"
 		}
 	}
 
@@ -75,125 +170,168 @@ func DownloadFileFromUrl(url *url.URL, options ...func(*Options) error) (string,
 
 	filename = getFilenameFromUrl(url)
 
-	id = uuid.New().String()
+	if defaultOptions.resumable {
+		// A stable, URL-derived id (rather than a fresh uuid) lets a later
+		// call land on the same ".part" file a previous, interrupted call
+		// left behind, so resuming actually has something to resume.
+		id = stableDownloadID(url)
+	} else {
+		id = uuid.New().String()
+	}
 	path = fmt.Sprintf("%v/%v", defaultOptions.baseFolder, id)
 	if err = os.MkdirAll(path, 0755); err != nil {
-		return "", err
+		return nil, err
 	}
 
 	fullpath = fmt.Sprintf("%v/%v", path, filename)
-	if err = downloadFile(fullpath, url, defaultOptions.maxSize); err != nil {
-		if pathErr = os.RemoveAll(path); err != nil {
-			log.Println(pathErr)
+
+	if defaultOptions.contentAddressed {
+		// A previous call for this exact URL may already have the content
+		// stored under its hash; if so, skip the network fetch, but still
+		// run this call's own checksum/mime/unarchive checks below against
+		// the already-stored file rather than trusting a previous call's.
+		if cachedDigest, cachedPath, cached = lookupContentAddressed(defaultOptions.baseFolder, url); cached {
+			if err = os.Symlink(cachedPath, fullpath); err != nil {
+				return nil, err
+			}
 		}
-		return "", err
 	}
 
-	if defaultOptions.mimeType != "" || defaultOptions.mimeGroups != nil {
-		if mimeType, err = getMimeType(fullpath); err != nil {
-			return "", err
+	if cached {
+		if checksumAlgo != "" {
+			if err = verifyChecksumFile(fullpath, checksumAlgo, checksumHex); err != nil {
+				if pathErr = os.RemoveAll(path); pathErr != nil {
+					log.Println(pathErr)
+				}
+				return nil, err
+			}
 		}
-
-		if defaultOptions.mimeType != "" && defaultOptions.mimeType != mimeType {
-			return "", ErrWrongMimeType
-		} else if !containsMimeGroup(mimeType, defaultOptions.mimeGroups) {
-			return "", ErrWrongMimeGroup
+	} else if err = downloadFile(ctx, fullpath, url, defaultOptions.maxSize, defaultOptions.httpClient, defaultOptions.requestHeaders, defaultOptions.userAgent, checksumAlgo, checksumHex, defaultOptions.resumable, defaultOptions.retryAttempts, defaultOptions.retryBackoff); err != nil {
+		if !(defaultOptions.resumable && isRetryableError(err)) {
+			if pathErr = os.RemoveAll(path); pathErr != nil {
+				log.Println(pathErr)
+			}
 		}
+		return nil, err
 	}
 
-	return fullpath, nil
+	if defaultOptions.mimeType != "" || defaultOptions.mimeGroups != nil || defaultOptions.renameByDetectedMime || defaultOptions.unarchive {
 
-}
+		if mimeType, extension, err = getMimeType(fullpath, defaultOptions.mimeDetector); err != nil {
+			return nil, err
+		}
 
-func containsMimeGroup(typ string, group []string) bool {
+		if defaultOptions.mimeType != "" || defaultOptions.mimeGroups != nil {
+			if defaultOptions.mimeType != "" && defaultOptions.mimeType != mimeType {
+				return nil, ErrWrongMimeType
+			} else if !containsMimeGroup(mimeType, defaultOptions.mimeGroups) {
+				return nil, ErrWrongMimeGroup
+			}
+		}
 
-	var item string
-	var result string
+		if defaultOptions.unarchive {
+			extractDir = fmt.Sprintf("%v/extracted", path)
+			if err = os.MkdirAll(extractDir, 0755); err != nil {
+				return nil, err
+			}
+			if err = unarchiveFile(fullpath, mimeType, extractDir, defaultOptions.unarchiveStrip, defaultOptions.maxSize); err != nil {
+				return nil, err
+			}
+			return &DownloadFileFromUrlResult{Path: extractDir}, nil
+		}
 
-	for _, item = range group {
-		result = fmt.Sprintf("%v/", item)
-		if result == typ[:len(result)] {
-			return true
+		if defaultOptions.renameByDetectedMime {
+			if fullpath, err = renameToExtension(fullpath, extension); err != nil {
+				return nil, err
+			}
 		}
+
 	}
 
-	return false
+	if defaultOptions.contentAddressed {
 
-}
+		if cached {
+			return &DownloadFileFromUrlResult{Path: cachedPath, Hash: cachedDigest}, nil
+		}
 
-func getMimeType(fullpath string) (string, error) {
+		var result *DownloadFileFromUrlResult
 
-	var file *os.File
-	var err error
-	var buf []byte
-	var amount int
-	var result string
+		if result, err = storeContentAddressed(fullpath, defaultOptions.baseFolder); err != nil {
+			return nil, err
+		}
 
-	if file, err = os.Open(fullpath); err != nil {
-		return "", err
-	}
+		if err = recordContentAddressed(defaultOptions.baseFolder, url, result.Hash); err != nil {
+			return nil, err
+		}
 
-	buf = make([]byte, 512)
-	if amount, err = file.Read(buf); err != nil {
-		return "", err
-	}
+		return result, nil
 
-	result = http.DetectContentType(buf[:amount])
+	}
 
-	return result, nil
+	return &DownloadFileFromUrlResult{Path: fullpath}, nil
 
 }
 
-func downloadFile(filename string, url *url.URL, maxSize int64) error {
+func downloadFile(ctx context.Context, filename string, url *url.URL, maxSize int64, client *http.Client, headers http.Header, userAgent string, checksumAlgo string, checksumHex string, resumable bool, retryAttempts int, retryBackoff time.Duration) error {
 
-	var file *os.File
-	var response *http.Response
+	var fetcher Fetcher
+	var ok bool
 	var err error
+	var partPath string
 
-	if file, err = os.Create(filename); err != nil {
-		return err
+	if fetcher, ok = lookupFetcher(url.Scheme); !ok {
+		return ErrUnsupportedScheme
+	}
+
+	// When the handler registered for the scheme is the built-in
+	// httpFetcher, it is replaced here by a fresh one carrying the per-call
+	// Options (client, headers, user agent). A scheme registered with a
+	// different Fetcher implementation is used as-is, ignoring those options.
+	if _, ok = fetcher.(*httpFetcher); ok {
+		fetcher = &httpFetcher{Client: client, Headers: headers, UserAgent: userAgent}
 	}
 
-	defer file.Close()
+	partPath = filename + ".part"
 
-	if response, err = http.Get(url.String()); err != nil {
+	if err = fetchWithRetry(ctx, fetcher, url, partPath, maxSize, resumable, retryAttempts, retryBackoff); err != nil {
 		return err
 	}
 
-	defer response.Body.Close()
-
-	if maxSize == 0 {
-		if _, err = io.Copy(file, response.Body); err != nil {
-			return err
-		}
-	} else {
-		if err = copyMax(file, response.Body, maxSize); err != nil {
+	if checksumAlgo != "" {
+		if err = verifyChecksumFile(partPath, checksumAlgo, checksumHex); err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return os.Rename(partPath, filename)
 
 }
 
-func copyMax(dst io.Writer, src io.Reader, n int64) error {
+// maxSizeWriter enforces maxSize against whatever is ultimately streamed
+// into it, which lets callers cap output regardless of which Fetcher is
+// doing the actual reading.
+type maxSizeWriter struct {
+	dst     io.Writer
+	limit   int64
+	written int64
+}
 
+func (w *maxSizeWriter) Write(p []byte) (int, error) {
+
+	var n int
 	var err error
-	var nextByte []byte
-	var nRead int
 
-	if _, err = io.CopyN(dst, src, n); err != nil {
-		return err
+	if w.written+int64(len(p)) > w.limit {
+		return 0, ErrMaxSizeExceeded
 	}
 
-	nextByte = make([]byte, 1)
-	nRead, _ = io.ReadFull(src, nextByte)
-
-	if nRead > 0 {
-		return ErrMaxSizeExceeded
+	if n, err = w.dst.Write(p); err != nil {
+		return n, err
 	}
 
-	return nil
+	w.written += int64(n)
+
+	return n, nil
 
 }
 
@@ -201,7 +339,7 @@ func getFilenameFromUrl(url *url.URL) string {
 
 	var result string
 
-	result = strings.Trim(path.Base(url.Path))
+	result = strings.TrimSpace(path.Base(url.Path))
 
 	if result == "" {
 		return "index.htm"
@@ -0,0 +1,104 @@
+package urldownloader
+
+import (
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+func (o *Options) SetMimeDetector(detector func(io.Reader) (string, string, error)) {
+	o.mimeDetector = detector
+}
+
+func (o *Options) SetRenameByDetectedMime(rename bool) {
+	o.renameByDetectedMime = rename
+}
+
+func containsMimeGroup(typ string, group []string) bool {
+
+	var item string
+	var result string
+
+	for _, item = range group {
+		result = item + "/"
+		if len(typ) >= len(result) && result == typ[:len(result)] {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// getMimeType sniffs the content at fullpath with detector, falling back
+// to the package default (github.com/gabriel-vasile/mimetype) when none
+// was injected via SetMimeDetector, and returns both the detected MIME
+// type and its canonical file extension (e.g. ".zip").
+func getMimeType(fullpath string, detector func(io.Reader) (string, string, error)) (string, string, error) {
+
+	var file *os.File
+	var err error
+	var mimeType string
+	var extension string
+
+	if file, err = os.Open(fullpath); err != nil {
+		return "", "", err
+	}
+
+	defer file.Close()
+
+	if detector == nil {
+		detector = defaultMimeDetector
+	}
+
+	if mimeType, extension, err = detector(file); err != nil {
+		return "", "", err
+	}
+
+	return mimeType, extension, nil
+
+}
+
+func defaultMimeDetector(r io.Reader) (string, string, error) {
+
+	var detected *mimetype.MIME
+	var err error
+
+	if detected, err = mimetype.DetectReader(r); err != nil {
+		return "", "", err
+	}
+
+	return detected.String(), detected.Extension(), nil
+
+}
+
+// renameToExtension renames fullpath so it carries extension (which
+// includes the leading dot, e.g. ".zip"), leaving it untouched if the
+// extension is empty or already matches.
+func renameToExtension(fullpath string, extension string) (string, error) {
+
+	var base string
+	var renamed string
+	var err error
+
+	if extension == "" {
+		return fullpath, nil
+	}
+
+	base = strings.TrimSuffix(fullpath, path.Ext(fullpath))
+	renamed = base + extension
+
+	if renamed == fullpath {
+		return fullpath, nil
+	}
+
+	if err = os.Rename(fullpath, renamed); err != nil {
+		return "", err
+	}
+
+	return renamed, nil
+
+}
@@ -0,0 +1,15 @@
+package urldownloader
+
+import "testing"
+
+func TestContainsMimeGroupShortType(t *testing.T) {
+
+	var result bool
+
+	result = containsMimeGroup("text", []string{"application"})
+
+	if result {
+		t.Errorf("containsMimeGroup(%q, %v) = true, want false", "text", []string{"application"})
+	}
+
+}
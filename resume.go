@@ -0,0 +1,200 @@
+package urldownloader
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+var ErrRangeNotSupported error = errors.New("Server did not honor the Range request")
+
+func (o *Options) SetResumable(resumable bool) {
+	o.resumable = resumable
+}
+
+func (o *Options) SetRetry(attempts int, backoff time.Duration) {
+	o.retryAttempts = attempts
+	o.retryBackoff = backoff
+}
+
+// stableDownloadID derives a deterministic id from target so a resumable
+// download lands in the same "<baseFolder>/<id>/<filename>.part" path on
+// every call, letting a later call find and continue a previous one's
+// partial file. A fresh uuid, by contrast, would never be reused.
+func stableDownloadID(target *url.URL) string {
+
+	var sum [sha256.Size]byte
+
+	sum = sha256.Sum256([]byte(target.String()))
+
+	return hex.EncodeToString(sum[:])
+
+}
+
+// ResumableFetcher is an optional capability a Fetcher may implement to
+// continue a previously interrupted download from a byte offset, e.g. via
+// an HTTP Range request. Fetchers that don't implement it simply restart
+// from scratch when resuming is requested.
+type ResumableFetcher interface {
+	Fetcher
+	FetchRange(ctx context.Context, url *url.URL, offset int64, dst io.Writer) (http.Header, error)
+}
+
+func (f *httpFetcher) FetchRange(ctx context.Context, url *url.URL, offset int64, dst io.Writer) (http.Header, error) {
+
+	var request *http.Request
+	var response *http.Response
+	var client *http.Client
+	var err error
+
+	if request, err = http.NewRequestWithContext(ctx, http.MethodGet, url.String(), nil); err != nil {
+		return nil, err
+	}
+
+	if f.Headers != nil {
+		request.Header = f.Headers.Clone()
+	}
+
+	if f.UserAgent != "" {
+		request.Header.Set("User-Agent", f.UserAgent)
+	}
+
+	request.Header.Set("Range", fmt.Sprintf("bytes=%v-", offset))
+
+	client = f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	if response, err = client.Do(request); err != nil {
+		return nil, err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode >= 500 {
+		return nil, ErrHTTPStatus{StatusCode: response.StatusCode}
+	}
+
+	if response.StatusCode != http.StatusPartialContent {
+		return nil, ErrRangeNotSupported
+	}
+
+	if _, err = io.Copy(dst, response.Body); err != nil {
+		return nil, err
+	}
+
+	return response.Header, nil
+
+}
+
+// fetchWithRetry downloads url into partPath, resuming from any existing
+// partial content when resumable is set and the fetcher supports it, and
+// retrying transient failures with exponential backoff up to attempts
+// times. When resumable is set, bytes already written to partPath survive
+// a failed attempt; otherwise each attempt starts partPath afresh.
+func fetchWithRetry(ctx context.Context, fetcher Fetcher, url *url.URL, partPath string, maxSize int64, resumable bool, attempts int, backoff time.Duration) error {
+
+	var err error
+	var attempt int
+	var wait time.Duration
+
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt = 0; attempt < attempts; attempt++ {
+
+		if err = fetchOnce(ctx, fetcher, url, partPath, maxSize, resumable); err == nil {
+			return nil
+		}
+
+		if attempt == attempts-1 || !isRetryableError(err) {
+			return err
+		}
+
+		wait = backoff * time.Duration(1<<uint(attempt))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+
+	}
+
+	return err
+
+}
+
+func fetchOnce(ctx context.Context, fetcher Fetcher, url *url.URL, partPath string, maxSize int64, resumable bool) error {
+
+	var file *os.File
+	var info os.FileInfo
+	var offset int64
+	var resumableFetcher ResumableFetcher
+	var ok bool
+	var err error
+	var dst io.Writer
+
+	if resumable {
+		if info, err = os.Stat(partPath); err == nil {
+			offset = info.Size()
+		}
+	}
+
+	if offset > 0 {
+		if resumableFetcher, ok = fetcher.(ResumableFetcher); !ok {
+			offset = 0
+		}
+	}
+
+	if offset > 0 {
+		if file, err = os.OpenFile(partPath, os.O_APPEND|os.O_WRONLY, 0644); err != nil {
+			return err
+		}
+	} else if file, err = os.Create(partPath); err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	dst = file
+	if maxSize != 0 {
+		dst = &maxSizeWriter{dst: dst, limit: maxSize, written: offset}
+	}
+
+	if offset > 0 {
+		_, err = resumableFetcher.FetchRange(ctx, url, offset, dst)
+	} else {
+		_, err = fetcher.Fetch(ctx, url, dst)
+	}
+
+	return err
+
+}
+
+func isRetryableError(err error) bool {
+
+	var statusErr ErrHTTPStatus
+	var netErr net.Error
+
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, io.ErrUnexpectedEOF)
+
+}
@@ -0,0 +1,26 @@
+package urldownloader
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestStableDownloadIDIsDeterministic(t *testing.T) {
+
+	var target *url.URL
+	var err error
+	var first string
+	var second string
+
+	if target, err = url.Parse("https://example.com/file.zip"); err != nil {
+		t.Fatalf("url.Parse() returned error: %v", err)
+	}
+
+	first = stableDownloadID(target)
+	second = stableDownloadID(target)
+
+	if first != second {
+		t.Errorf("stableDownloadID() = %q then %q, want identical ids for the same URL", first, second)
+	}
+
+}
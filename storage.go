@@ -0,0 +1,119 @@
+package urldownloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func (o *Options) SetContentAddressed(contentAddressed bool) {
+	o.contentAddressed = contentAddressed
+}
+
+// DownloadFileFromUrlResult is returned by DownloadFileFromUrlResultContext.
+// Hash is only populated when SetContentAddressed is enabled.
+type DownloadFileFromUrlResult struct {
+	Path string
+	Hash string
+}
+
+// urlIndexPath returns where the content hash previously resolved for
+// target is remembered, keyed by the URL itself rather than its content,
+// so a later call for the same URL can be recognized before anything is
+// downloaded.
+func urlIndexPath(baseFolder string, target *url.URL) string {
+	var sum [sha256.Size]byte
+	sum = sha256.Sum256([]byte(target.String()))
+	return filepath.Join(baseFolder, ".urlindex", hex.EncodeToString(sum[:]))
+}
+
+// lookupContentAddressed reports the content hash and on-disk path
+// previously stored for target, provided that content is still present,
+// letting the caller skip the download outright when it is.
+func lookupContentAddressed(baseFolder string, target *url.URL) (string, string, bool) {
+
+	var data []byte
+	var digest string
+	var contentPath string
+	var err error
+
+	if data, err = os.ReadFile(urlIndexPath(baseFolder, target)); err != nil {
+		return "", "", false
+	}
+
+	digest = strings.TrimSpace(string(data))
+	if digest == "" {
+		return "", "", false
+	}
+
+	contentPath = filepath.Join(baseFolder, digest[:2], digest)
+	if _, err = os.Stat(contentPath); err != nil {
+		return "", "", false
+	}
+
+	return digest, contentPath, true
+
+}
+
+// recordContentAddressed remembers that target last resolved to digest,
+// so a future lookupContentAddressed call for the same URL can short-
+// circuit the download.
+func recordContentAddressed(baseFolder string, target *url.URL, digest string) error {
+
+	var indexPath string
+	var err error
+
+	indexPath = urlIndexPath(baseFolder, target)
+	if err = os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(indexPath, []byte(digest), 0644)
+
+}
+
+// storeContentAddressed relocates the already-downloaded file at fullpath
+// into "<baseFolder>/<sha256[0:2]>/<sha256>", short-circuiting the copy
+// when identical content is already stored there, and leaves a symlink at
+// fullpath pointing to the content-addressed path so the original
+// per-download location keeps working.
+func storeContentAddressed(fullpath string, baseFolder string) (*DownloadFileFromUrlResult, error) {
+
+	var digest string
+	var contentDir string
+	var contentPath string
+	var err error
+
+	if digest, err = hashFile(fullpath, "sha256"); err != nil {
+		return nil, err
+	}
+
+	contentDir = filepath.Join(baseFolder, digest[:2])
+	contentPath = filepath.Join(contentDir, digest)
+
+	if err = os.MkdirAll(contentDir, 0755); err != nil {
+		return nil, err
+	}
+
+	if _, err = os.Stat(contentPath); err == nil {
+		if err = os.Remove(fullpath); err != nil {
+			return nil, err
+		}
+	} else if os.IsNotExist(err) {
+		if err = os.Rename(fullpath, contentPath); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+
+	if err = os.Symlink(contentPath, fullpath); err != nil {
+		return nil, err
+	}
+
+	return &DownloadFileFromUrlResult{Path: contentPath, Hash: digest}, nil
+
+}